@@ -0,0 +1,67 @@
+package pool
+
+// Config holds the pool's daemon-facing settings, loaded from config.json.
+type Config struct {
+	Address               string        `json:"address"`
+	Upstream              []Upstream    `json:"upstream"`
+	UpstreamCheckInterval string        `json:"upstreamCheckInterval"`
+	Subscriptions         Subscriptions `json:"subscriptions"`
+	TemplatePush          TemplatePush  `json:"templatePush"`
+}
+
+// TemplatePush configures how StratumServer learns that the chain tip has
+// moved. PollInterval always runs alongside ZMQEndpoint, if set, so a missed
+// notification can't stall job delivery.
+type TemplatePush struct {
+	// ZMQEndpoint, when set, subscribes to the daemon's hashblock/
+	// chain_main topic over ZMQ and refreshes the template on each
+	// notification, in addition to the PollInterval refresh.
+	ZMQEndpoint string `json:"zmqEndpoint"`
+	// UseHashHint passes the last known prev_hash to getblocktemplate so
+	// the daemon can reply immediately with no blob when the tip hasn't moved.
+	UseHashHint  bool   `json:"useHashHint"`
+	PollInterval string `json:"pollInterval"`
+}
+
+// Subscriptions configures the push-notification feed that lets external
+// dashboards and monitoring subscribe to server-side events instead of
+// polling the stats API.
+type Subscriptions struct {
+	Enabled        bool   `json:"enabled"`
+	Listen         string `json:"listen"`
+	WSListen       string `json:"wsListen"`
+	WSPath         string `json:"wsPath"`
+	MaxSessions    int    `json:"maxSessions"`
+	SessionTimeout string `json:"sessionTimeout"`
+}
+
+// Upstream describes a single daemon RPC endpoint. Pools may list several;
+// StratumServer fails over between them, preferring the first one in the
+// list that isn't marked sick.
+type Upstream struct {
+	Name     string `json:"name"`
+	Host     string `json:"host"`
+	Port     int    `json:"port"`
+	Timeout  string `json:"timeout"`
+	User     string `json:"user"`
+	Password string `json:"password"`
+	Enabled  bool   `json:"enabled"`
+
+	// Socket, when set, talks to a co-located daemon over a Unix domain
+	// socket (e.g. "/var/run/monerod.sock") instead of Host/Port.
+	Socket string `json:"socket"`
+
+	// TLS enables https:// against Host/Port for daemons behind TLS
+	// termination. Ignored when Socket is set.
+	TLS                   bool   `json:"tls"`
+	TLSCACert             string `json:"tlsCaCert"`
+	TLSClientCert         string `json:"tlsClientCert"`
+	TLSClientKey          string `json:"tlsClientKey"`
+	TLSInsecureSkipVerify bool   `json:"tlsInsecureSkipVerify"`
+
+	// MaxIdleConnsPerHost and IdleConnTimeout tune the client's connection
+	// pool so high call volumes (submitblock/getblocktemplate) reuse TCP
+	// connections instead of churning them. Both default when zero.
+	MaxIdleConnsPerHost int    `json:"maxIdleConnsPerHost"`
+	IdleConnTimeout     string `json:"idleConnTimeout"`
+}
@@ -3,7 +3,6 @@ package rpc
 import (
 	"bytes"
 	"encoding/json"
-	"errors"
 	"fmt"
 	"net/http"
 	"net/url"
@@ -59,21 +58,62 @@ type ValidateAddressReply struct {
 }
 
 type JSONRpcResp struct {
-	Id     *json.RawMessage       `json:"id"`
-	Result *json.RawMessage       `json:"result"`
-	Error  map[string]interface{} `json:"error"`
+	Id     *json.RawMessage `json:"id"`
+	Result *json.RawMessage `json:"result"`
+	Error  *RPCError        `json:"error"`
+}
+
+// Known daemon error codes returned on submitblock, used to tell expected
+// rejections (a share simply wasn't good enough, or arrived late) apart
+// from genuine daemon trouble.
+const (
+	ErrCodeLowDifficultyShare = -5
+	ErrCodeBlockNotAccepted   = -7
+	ErrCodeStale              = -8
+	ErrCodeDuplicate          = -9
+)
+
+// RPCError is a JSON-RPC 2.0 error object. Unlike a plain errors.New, it
+// keeps the daemon's numeric code around so callers can distinguish
+// expected application-level rejections (stale share, duplicate, low
+// difficulty) from real trouble without string-matching Message.
+type RPCError struct {
+	Code    int             `json:"code"`
+	Message string          `json:"message"`
+	Data    json.RawMessage `json:"data,omitempty"`
+}
+
+func (e *RPCError) Error() string {
+	return fmt.Sprintf("rpc error %d: %s", e.Code, e.Message)
+}
+
+// IsExpectedRejection reports whether the error is a normal submitblock
+// rejection (the share didn't meet difficulty, arrived stale, was a
+// duplicate, or the block lost a race) rather than a sign of daemon
+// trouble, so the stratum layer can log it without treating the upstream
+// as unhealthy.
+func (e *RPCError) IsExpectedRejection() bool {
+	switch e.Code {
+	case ErrCodeLowDifficultyShare, ErrCodeBlockNotAccepted, ErrCodeStale, ErrCodeDuplicate:
+		return true
+	}
+	return false
 }
 
 func NewRPCClient(cfg *pool.Upstream) (*RPCClient, error) {
-	rawUrl := fmt.Sprintf("http://%s:%v/json_rpc", cfg.Host, cfg.Port)
-	url, err := url.Parse(rawUrl)
+	url, err := url.Parse(upstreamURL(cfg))
+	if err != nil {
+		return nil, err
+	}
+	transport, err := newTransport(cfg)
 	if err != nil {
 		return nil, err
 	}
 	rpcClient := &RPCClient{Name: cfg.Name, Url: url, login: cfg.User, password: cfg.Password}
 	timeout, _ := time.ParseDuration(cfg.Timeout)
 	rpcClient.client = &http.Client{
-		Timeout: timeout,
+		Timeout:   timeout,
+		Transport: transport,
 	}
 	return rpcClient, nil
 }
@@ -91,6 +131,31 @@ func (r *RPCClient) GetBlockTemplate(reserveSize int, address string) (*GetBlock
 	return reply, err
 }
 
+// GetBlockTemplateSince behaves like GetBlockTemplate but passes prevHash
+// as a hint so a daemon that supports it can reply immediately without a
+// full blob when the tip hasn't moved past prevHash. Callers should treat
+// a nil, nil return as "no change".
+func (r *RPCClient) GetBlockTemplateSince(reserveSize int, address, prevHash string) (*GetBlockTemplateReply, error) {
+	params := map[string]interface{}{"reserve_size": reserveSize, "wallet_address": address}
+	if prevHash != "" {
+		params["prev_block"] = prevHash
+	}
+	rpcResp, err := r.doPost(r.Url.String(), "getblocktemplate", params)
+	if err != nil {
+		return nil, err
+	}
+	var reply *GetBlockTemplateReply
+	if rpcResp.Result != nil {
+		if err = json.Unmarshal(*rpcResp.Result, &reply); err != nil {
+			return nil, err
+		}
+	}
+	if reply != nil && prevHash != "" && reply.PrevHash == prevHash {
+		return nil, nil
+	}
+	return reply, nil
+}
+
 func (r *RPCClient) GetInfo() (*GetInfoReply, error) {
 	params := make(map[string]interface{})
 	rpcResp, err := r.doPost(r.Url.String(), "get_info", params)
@@ -140,11 +205,12 @@ func (r *RPCClient) doPost(url, method string, params interface{}) (*JSONRpcResp
 	}
 	defer resp.Body.Close()
 
-	/*
-		if resp.StatusCode < 200 || resp.StatusCode >= 400 {
-			return nil, errors.New(resp.Status)
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		if resp.StatusCode >= 500 {
+			r.markSick()
 		}
-	*/
+		return nil, fmt.Errorf("upstream %s returned HTTP %s", r.Name, resp.Status)
+	}
 
 	var rpcResp *JSONRpcResp
 	err = json.NewDecoder(resp.Body).Decode(&rpcResp)
@@ -153,10 +219,12 @@ func (r *RPCClient) doPost(url, method string, params interface{}) (*JSONRpcResp
 		return nil, err
 	}
 	if rpcResp.Error != nil {
-		r.markSick()
-		return nil, errors.New(rpcResp.Error["message"].(string))
+		// Application-level JSON-RPC errors (stale share, duplicate, low
+		// difficulty, block not accepted, ...) are expected in normal
+		// operation and shouldn't count against upstream health.
+		return rpcResp, rpcResp.Error
 	}
-	return rpcResp, err
+	return rpcResp, nil
 }
 
 func (r *RPCClient) Check(reserveSize int, address string) (bool, error) {
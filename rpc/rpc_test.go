@@ -0,0 +1,27 @@
+package rpc
+
+import "testing"
+
+func TestRPCErrorIsExpectedRejection(t *testing.T) {
+	cases := []struct {
+		name string
+		code int
+		want bool
+	}{
+		{"low difficulty share", ErrCodeLowDifficultyShare, true},
+		{"block not accepted", ErrCodeBlockNotAccepted, true},
+		{"stale", ErrCodeStale, true},
+		{"duplicate", ErrCodeDuplicate, true},
+		{"unknown code", -32000, false},
+		{"zero", 0, false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			err := &RPCError{Code: c.code, Message: "test"}
+			if got := err.IsExpectedRejection(); got != c.want {
+				t.Fatalf("IsExpectedRejection() = %v, want %v", got, c.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,93 @@
+package rpc
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"time"
+
+	"truenft.tech/tnft-stratum/v2/pool"
+)
+
+const (
+	defaultMaxIdleConnsPerHost = 32
+	defaultIdleConnTimeout     = 90 * time.Second
+)
+
+// upstreamURL builds the json_rpc endpoint URL for cfg. Unix-socket
+// upstreams use a fixed host, since the actual path is carried by the
+// transport's DialContext rather than the URL.
+func upstreamURL(cfg *pool.Upstream) string {
+	if cfg.Socket != "" {
+		return "http://unix/json_rpc"
+	}
+	scheme := "http"
+	if cfg.TLS {
+		scheme = "https"
+	}
+	return fmt.Sprintf("%s://%s:%v/json_rpc", scheme, cfg.Host, cfg.Port)
+}
+
+// newTransport builds an http.Transport for cfg, tuned for connection
+// reuse under high call volume and configured for whichever of Unix
+// socket, TLS, or plain HTTP the upstream calls for.
+func newTransport(cfg *pool.Upstream) (*http.Transport, error) {
+	maxIdle := cfg.MaxIdleConnsPerHost
+	if maxIdle == 0 {
+		maxIdle = defaultMaxIdleConnsPerHost
+	}
+	idleTimeout := defaultIdleConnTimeout
+	if cfg.IdleConnTimeout != "" {
+		parsed, err := time.ParseDuration(cfg.IdleConnTimeout)
+		if err != nil {
+			return nil, fmt.Errorf("invalid idleConnTimeout %q: %s", cfg.IdleConnTimeout, err)
+		}
+		idleTimeout = parsed
+	}
+
+	transport := &http.Transport{
+		MaxIdleConnsPerHost: maxIdle,
+		IdleConnTimeout:     idleTimeout,
+	}
+
+	if cfg.Socket != "" {
+		socket := cfg.Socket
+		dialer := &net.Dialer{}
+		transport.DialContext = func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return dialer.DialContext(ctx, "unix", socket)
+		}
+		return transport, nil
+	}
+
+	if cfg.TLS {
+		tlsConfig := &tls.Config{InsecureSkipVerify: cfg.TLSInsecureSkipVerify}
+
+		if cfg.TLSCACert != "" {
+			pem, err := os.ReadFile(cfg.TLSCACert)
+			if err != nil {
+				return nil, fmt.Errorf("reading tlsCaCert: %s", err)
+			}
+			pool := x509.NewCertPool()
+			if !pool.AppendCertsFromPEM(pem) {
+				return nil, fmt.Errorf("no certificates found in %s", cfg.TLSCACert)
+			}
+			tlsConfig.RootCAs = pool
+		}
+
+		if cfg.TLSClientCert != "" || cfg.TLSClientKey != "" {
+			cert, err := tls.LoadX509KeyPair(cfg.TLSClientCert, cfg.TLSClientKey)
+			if err != nil {
+				return nil, fmt.Errorf("loading client cert/key: %s", err)
+			}
+			tlsConfig.Certificates = []tls.Certificate{cert}
+		}
+
+		transport.TLSClientConfig = tlsConfig
+	}
+
+	return transport, nil
+}
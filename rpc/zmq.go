@@ -0,0 +1,37 @@
+package rpc
+
+import (
+	"context"
+	"log"
+
+	zmq4 "github.com/go-zeromq/zmq4"
+)
+
+// SubscribeTipChanges dials endpoint over ZMQ SUB and invokes onTip from a
+// background goroutine every time a message arrives on one of topics (e.g.
+// "hashblock" or "json-minimal-chain_main"). It returns once the socket is
+// dialed and subscribed; the goroutine runs until Recv errors.
+func SubscribeTipChanges(endpoint string, topics []string, onTip func()) error {
+	sock := zmq4.NewSub(context.Background())
+	if err := sock.Dial(endpoint); err != nil {
+		return err
+	}
+	for _, topic := range topics {
+		if err := sock.SetOption(zmq4.OptionSubscribe, topic); err != nil {
+			sock.Close()
+			return err
+		}
+	}
+
+	go func() {
+		defer sock.Close()
+		for {
+			if _, err := sock.Recv(); err != nil {
+				log.Printf("ZMQ subscription to %s lost: %s", endpoint, err)
+				return
+			}
+			onTip()
+		}
+	}()
+	return nil
+}
@@ -8,6 +8,7 @@ import (
 	"math/big"
 
 	"truenft.tech/tnft-stratum/v2/cnutil"
+	"truenft.tech/tnft-stratum/v2/rpc"
 )
 
 type BlockTemplate struct {
@@ -35,12 +36,23 @@ func (b *BlockTemplate) nextBlob(extraNonce uint32, instanceId []byte) string {
 
 func (s *StratumServer) fetchBlockTemplate() bool {
 	r := s.rpc()
-	reply, err := r.GetBlockTemplate(8, s.config.Address)
+	t := s.currentBlockTemplate()
+
+	var reply *rpc.GetBlockTemplateReply
+	var err error
+	if s.config.TemplatePush.UseHashHint && t != nil {
+		reply, err = r.GetBlockTemplateSince(8, s.config.Address, t.prevHash)
+	} else {
+		reply, err = r.GetBlockTemplate(8, s.config.Address)
+	}
 	if err != nil {
 		log.Printf("Error while refreshing block template: %s", err)
 		return false
 	}
-	t := s.currentBlockTemplate()
+	if reply == nil {
+		// Daemon confirmed the tip hasn't moved past t.prevHash.
+		return false
+	}
 
 	if t != nil && t.prevHash == reply.PrevHash {
 		// Fallback to height comparison
@@ -63,5 +75,13 @@ func (s *StratumServer) fetchBlockTemplate() bool {
 	}
 	newTemplate.buffer, _ = hex.DecodeString(reply.Blob)
 	s.blockTemplate.Store(&newTemplate)
+
+	if s.sessions != nil {
+		s.sessions.broadcast(EventBlockTemplateNew, map[string]interface{}{
+			"height":     reply.Height,
+			"difficulty": reply.Difficulty,
+			"prev_hash":  reply.PrevHash,
+		})
+	}
 	return true
 }
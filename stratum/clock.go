@@ -0,0 +1,16 @@
+package stratum
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// nowUnix and atomicStoreNow exist so the subscription session bookkeeping
+// can stamp activity times without racing on a plain int64.
+func nowUnix() int64 {
+	return time.Now().Unix()
+}
+
+func atomicStoreNow(t *int64) {
+	atomic.StoreInt64(t, nowUnix())
+}
@@ -0,0 +1,356 @@
+package stratum
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"log"
+	"net"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// startSubscriptions wires up s.sessions from config and starts the TCP
+// and/or WebSocket listeners it configures. Safe to call with
+// subscriptions disabled; s.sessions stays nil and broadcast call sites
+// no-op.
+func (s *StratumServer) startSubscriptions() {
+	cfg := s.config.Subscriptions
+	if !cfg.Enabled {
+		return
+	}
+	idleTimeout, _ := time.ParseDuration(cfg.SessionTimeout)
+	s.sessions = newSessionManager(cfg.MaxSessions, idleTimeout)
+	go s.sessions.reapLoop()
+
+	if cfg.Listen != "" {
+		if err := s.sessions.ListenTCP(cfg.Listen); err != nil {
+			log.Printf("Failed to start subscription TCP listener: %s", err)
+		}
+	}
+	if cfg.WSListen != "" {
+		path := cfg.WSPath
+		if path == "" {
+			path = "/"
+		}
+		if err := s.sessions.ListenWS(cfg.WSListen, path); err != nil {
+			log.Printf("Failed to start subscription WebSocket listener: %s", err)
+		}
+	}
+}
+
+// Event names delivered as JSON-RPC 2.0 notifications (no "id") to
+// subscribers of the push feed. This server doesn't yet have a share
+// accept/reject path of its own to hook, so share.accepted/share.rejected
+// aren't offered here; add them alongside whatever processes submitted
+// shares once that lands.
+const (
+	EventBlockTemplateNew = "blocktemplate.new"
+	EventBlockFound       = "block.found"
+	EventUpstreamStatus   = "upstream.status"
+)
+
+// rpcNotification is a JSON-RPC 2.0 notification: it carries no "id", so
+// subscribers must not reply to it.
+type rpcNotification struct {
+	Jsonrpc string      `json:"jsonrpc"`
+	Method  string      `json:"method"`
+	Params  interface{} `json:"params"`
+}
+
+// subscriber is a single connected client, reached either over a raw
+// line-delimited TCP socket or a WebSocket connection.
+type subscriber struct {
+	id     string
+	out    chan []byte
+	events map[string]bool
+
+	conn net.Conn
+	ws   *websocket.Conn
+
+	lastSeen int64
+	closed   bool
+}
+
+func (sub *subscriber) touch() {
+	atomicStoreNow(&sub.lastSeen)
+}
+
+func (sub *subscriber) write(b []byte) {
+	select {
+	case sub.out <- b:
+	default:
+		// Subscriber isn't draining fast enough; drop the notification
+		// rather than block the broadcaster on a slow client.
+	}
+}
+
+// sessionManager tracks subscriber connections and fans out server-side
+// events to whichever of them have subscribed. It backs both the raw TCP
+// JSON-RPC listener and the WebSocket listener.
+type sessionManager struct {
+	sync.RWMutex
+	subscribers map[string]*subscriber
+	maxSessions int
+	idleTimeout time.Duration
+}
+
+func newSessionManager(maxSessions int, idleTimeout time.Duration) *sessionManager {
+	return &sessionManager{
+		subscribers: make(map[string]*subscriber),
+		maxSessions: maxSessions,
+		idleTimeout: idleTimeout,
+	}
+}
+
+func (m *sessionManager) add(sub *subscriber) bool {
+	m.Lock()
+	defer m.Unlock()
+	if m.maxSessions > 0 && len(m.subscribers) >= m.maxSessions {
+		return false
+	}
+	m.subscribers[sub.id] = sub
+	return true
+}
+
+// closeSubscriberLocked closes sub's connection and out channel so its
+// reader and writer goroutines unwind. Caller must hold m's lock and have
+// already removed sub from m.subscribers.
+func (m *sessionManager) closeSubscriberLocked(sub *subscriber) {
+	if sub.closed {
+		return
+	}
+	sub.closed = true
+	close(sub.out)
+	if sub.conn != nil {
+		sub.conn.Close()
+	}
+	if sub.ws != nil {
+		sub.ws.Close()
+	}
+}
+
+func (m *sessionManager) remove(id string) {
+	m.Lock()
+	sub, ok := m.subscribers[id]
+	delete(m.subscribers, id)
+	if ok {
+		m.closeSubscriberLocked(sub)
+	}
+	m.Unlock()
+}
+
+func (m *sessionManager) subscribe(id, event string) {
+	m.RLock()
+	sub, ok := m.subscribers[id]
+	m.RUnlock()
+	if !ok {
+		return
+	}
+	m.Lock()
+	sub.events[event] = true
+	m.Unlock()
+}
+
+func (m *sessionManager) unsubscribe(id, event string) {
+	m.RLock()
+	sub, ok := m.subscribers[id]
+	m.RUnlock()
+	if !ok {
+		return
+	}
+	m.Lock()
+	delete(sub.events, event)
+	m.Unlock()
+}
+
+// broadcast delivers a JSON-RPC notification to every subscriber of event.
+func (m *sessionManager) broadcast(event string, params interface{}) {
+	data, err := json.Marshal(rpcNotification{Jsonrpc: "2.0", Method: event, Params: params})
+	if err != nil {
+		log.Printf("Failed to marshal %s notification: %s", event, err)
+		return
+	}
+	data = append(data, '\n')
+
+	m.RLock()
+	defer m.RUnlock()
+	for _, sub := range m.subscribers {
+		if sub.events[event] {
+			sub.write(data)
+		}
+	}
+}
+
+// reapIdleSessions drops subscribers that haven't sent a request (including
+// a subscribe/unsubscribe) within idleTimeout.
+func (m *sessionManager) reapIdleSessions() {
+	if m.idleTimeout <= 0 {
+		return
+	}
+	deadline := nowUnix() - int64(m.idleTimeout/time.Second)
+
+	m.Lock()
+	defer m.Unlock()
+	for id, sub := range m.subscribers {
+		if atomic.LoadInt64(&sub.lastSeen) < deadline {
+			delete(m.subscribers, id)
+			m.closeSubscriberLocked(sub)
+		}
+	}
+}
+
+func (m *sessionManager) reapLoop() {
+	if m.idleTimeout <= 0 {
+		return
+	}
+	ticker := time.NewTicker(m.idleTimeout / 2)
+	defer ticker.Stop()
+	for range ticker.C {
+		m.reapIdleSessions()
+	}
+}
+
+// ListenTCP serves the push feed as newline-delimited JSON-RPC 2.0 over a
+// plain TCP socket.
+func (m *sessionManager) ListenTCP(addr string) error {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				if errors.Is(err, net.ErrClosed) {
+					return
+				}
+				var netErr net.Error
+				if errors.As(err, &netErr) && netErr.Temporary() {
+					log.Printf("Subscription listener accept error, retrying: %s", err)
+					continue
+				}
+				log.Printf("Subscription listener stopped: %s", err)
+				return
+			}
+			go m.serveTCP(conn)
+		}
+	}()
+	return nil
+}
+
+func (m *sessionManager) serveTCP(conn net.Conn) {
+	sub := &subscriber{
+		id:     conn.RemoteAddr().String(),
+		out:    make(chan []byte, 64),
+		events: make(map[string]bool),
+		conn:   conn,
+	}
+	sub.touch()
+	if !m.add(sub) {
+		conn.Close()
+		return
+	}
+	defer m.remove(sub.id)
+
+	go func() {
+		for b := range sub.out {
+			if _, err := conn.Write(b); err != nil {
+				return
+			}
+		}
+	}()
+
+	scanner := bufio.NewScanner(conn)
+	for scanner.Scan() {
+		sub.touch()
+		m.handleRequest(sub, scanner.Bytes())
+	}
+}
+
+var upgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+	CheckOrigin:     func(r *http.Request) bool { return true },
+}
+
+// ListenWS serves the push feed as JSON-RPC 2.0 over WebSocket at path.
+func (m *sessionManager) ListenWS(addr, path string) error {
+	mux := http.NewServeMux()
+	mux.HandleFunc(path, func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			log.Printf("WebSocket upgrade failed: %s", err)
+			return
+		}
+		m.serveWS(conn)
+	})
+	go func() {
+		if err := http.ListenAndServe(addr, mux); err != nil {
+			log.Printf("Subscription WebSocket listener stopped: %s", err)
+		}
+	}()
+	return nil
+}
+
+func (m *sessionManager) serveWS(conn *websocket.Conn) {
+	sub := &subscriber{
+		id:     conn.RemoteAddr().String(),
+		out:    make(chan []byte, 64),
+		events: make(map[string]bool),
+		ws:     conn,
+	}
+	sub.touch()
+	if !m.add(sub) {
+		conn.Close()
+		return
+	}
+	defer m.remove(sub.id)
+
+	go func() {
+		for b := range sub.out {
+			if err := conn.WriteMessage(websocket.TextMessage, b); err != nil {
+				return
+			}
+		}
+	}()
+
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		sub.touch()
+		m.handleRequest(sub, data)
+	}
+}
+
+type subscribeRequest struct {
+	Jsonrpc string      `json:"jsonrpc"`
+	Id      interface{} `json:"id"`
+	Method  string      `json:"method"`
+	Params  []string    `json:"params"`
+}
+
+func (m *sessionManager) handleRequest(sub *subscriber, data []byte) {
+	var req subscribeRequest
+	if err := json.Unmarshal(data, &req); err != nil {
+		return
+	}
+	switch req.Method {
+	case "subscribe":
+		for _, event := range req.Params {
+			m.subscribe(sub.id, event)
+		}
+	case "unsubscribe":
+		for _, event := range req.Params {
+			m.unsubscribe(sub.id, event)
+		}
+	default:
+		log.Printf("Unknown subscription method %q from %s", req.Method, sub.id)
+	}
+}
@@ -0,0 +1,89 @@
+package stratum
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+func newTestSubscriber(id string) *subscriber {
+	return &subscriber{
+		id:     id,
+		out:    make(chan []byte, 8),
+		events: make(map[string]bool),
+	}
+}
+
+func TestSessionManagerSubscribeUnsubscribe(t *testing.T) {
+	m := newSessionManager(0, 0)
+	sub := newTestSubscriber("a")
+	if !m.add(sub) {
+		t.Fatal("add() = false, want true")
+	}
+
+	m.subscribe(sub.id, EventBlockFound)
+	if !sub.events[EventBlockFound] {
+		t.Fatal("expected subscription to be recorded")
+	}
+
+	m.unsubscribe(sub.id, EventBlockFound)
+	if sub.events[EventBlockFound] {
+		t.Fatal("expected unsubscribe to clear the subscription")
+	}
+}
+
+func TestSessionManagerBroadcastOnlyReachesSubscribers(t *testing.T) {
+	m := newSessionManager(0, 0)
+	subscribed := newTestSubscriber("subscribed")
+	other := newTestSubscriber("other")
+	m.add(subscribed)
+	m.add(other)
+	m.subscribe(subscribed.id, EventBlockFound)
+
+	m.broadcast(EventBlockFound, map[string]string{"hash": "deadbeef"})
+
+	select {
+	case <-subscribed.out:
+	default:
+		t.Fatal("subscribed client should have received the notification")
+	}
+
+	select {
+	case <-other.out:
+		t.Fatal("unsubscribed client should not have received the notification")
+	default:
+	}
+}
+
+func TestReapIdleSessionsClosesTheConnection(t *testing.T) {
+	serverConn, clientConn := net.Pipe()
+	t.Cleanup(func() { clientConn.Close() })
+
+	m := newSessionManager(0, time.Nanosecond)
+	sub := newTestSubscriber("idle")
+	sub.conn = serverConn
+	sub.lastSeen = 0
+	m.add(sub)
+
+	m.reapIdleSessions()
+
+	if _, ok := m.subscribers[sub.id]; ok {
+		t.Fatal("expected the idle subscriber to be removed")
+	}
+	if _, ok := <-sub.out; ok {
+		t.Fatal("expected sub.out to be closed")
+	}
+	if _, err := clientConn.Write([]byte("x")); err == nil {
+		t.Fatal("expected the underlying connection to be closed")
+	}
+}
+
+func TestSessionManagerAddRespectsMaxSessions(t *testing.T) {
+	m := newSessionManager(1, 0)
+	if !m.add(newTestSubscriber("a")) {
+		t.Fatal("first add() = false, want true")
+	}
+	if m.add(newTestSubscriber("b")) {
+		t.Fatal("second add() = true, want false once maxSessions is reached")
+	}
+}
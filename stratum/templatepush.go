@@ -0,0 +1,34 @@
+package stratum
+
+import (
+	"log"
+	"time"
+
+	"truenft.tech/tnft-stratum/v2/rpc"
+)
+
+const defaultTemplatePollInterval = 5 * time.Second
+
+func (s *StratumServer) listenForBlockTemplateUpdates() {
+	cfg := s.config.TemplatePush
+
+	if cfg.ZMQEndpoint != "" {
+		topics := []string{"hashblock", "json-minimal-chain_main"}
+		err := rpc.SubscribeTipChanges(cfg.ZMQEndpoint, topics, func() {
+			s.fetchBlockTemplate()
+		})
+		if err != nil {
+			log.Printf("Failed to subscribe to %s for tip changes, falling back to polling only: %s", cfg.ZMQEndpoint, err)
+		}
+	}
+
+	interval, err := time.ParseDuration(cfg.PollInterval)
+	if err != nil || interval == 0 {
+		interval = defaultTemplatePollInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		s.fetchBlockTemplate()
+	}
+}
@@ -0,0 +1,131 @@
+package stratum
+
+import (
+	"errors"
+	"log"
+	"sync/atomic"
+	"time"
+
+	"truenft.tech/tnft-stratum/v2/rpc"
+)
+
+// rpc returns the currently-selected upstream, i.e. the first one in
+// s.upstreams that checkUpstreams last found to be alive.
+func (s *StratumServer) rpc() *rpc.RPCClient {
+	i := atomic.LoadInt32(&s.upstream)
+	return s.upstreams[i]
+}
+
+// firstAliveUpstream returns the index of the first true entry in alive,
+// i.e. the first upstream that passed its health check. It does not
+// consider which upstream is currently selected: an earlier upstream that
+// passes again is chosen over the current one. found is false when every
+// entry is false, in
+// which case idx is meaningless and must not be used to switch upstreams.
+func firstAliveUpstream(alive []bool) (idx int32, found bool) {
+	for i, ok := range alive {
+		if ok {
+			return int32(i), true
+		}
+	}
+	return 0, false
+}
+
+// checkUpstreams polls every configured upstream's health and switches the
+// active one to the first candidate whose Check succeeded and which isn't
+// sick. If no upstream passes, s.upstream is left untouched rather than
+// failing over to whichever index happens to be zero.
+func (s *StratumServer) checkUpstreams() {
+	alive := make([]bool, len(s.upstreams))
+	for i, u := range s.upstreams {
+		ok, err := u.Check(8, s.config.Address)
+		if err != nil {
+			log.Printf("Upstream %v (%s) check failed: %s", i, u.Name, err)
+		}
+		alive[i] = ok
+	}
+
+	candidate, found := firstAliveUpstream(alive)
+	if !found {
+		log.Printf("All upstreams are sick")
+		return
+	}
+
+	if s.upstream != candidate {
+		log.Printf("Switching to %v upstream", s.upstreams[candidate].Name)
+		atomic.StoreInt32(&s.upstream, candidate)
+		if s.sessions != nil {
+			s.sessions.broadcast(EventUpstreamStatus, s.upstreamStatsList())
+		}
+	}
+}
+
+// listenForUpstreamStatusChanges runs the periodic health checker for the
+// configured upstreams until the server shuts down.
+func (s *StratumServer) listenForUpstreamStatusChanges() {
+	interval, err := time.ParseDuration(s.config.UpstreamCheckInterval)
+	if err != nil || interval == 0 {
+		interval = 5 * time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		s.checkUpstreams()
+	}
+}
+
+// broadcastSubmitBlock submits hash to every healthy upstream, not just the
+// active one, so a found block still propagates if the primary node is
+// behind or wedged.
+func (s *StratumServer) broadcastSubmitBlock(hash string) {
+	for _, u := range s.upstreams {
+		if u.Sick() {
+			continue
+		}
+		go func(u *rpc.RPCClient) {
+			_, err := u.SubmitBlock(hash)
+			if err == nil {
+				if s.sessions != nil {
+					s.sessions.broadcast(EventBlockFound, map[string]interface{}{
+						"hash":     hash,
+						"upstream": u.Name,
+					})
+				}
+				return
+			}
+			var rpcErr *rpc.RPCError
+			if errors.As(err, &rpcErr) && rpcErr.IsExpectedRejection() {
+				log.Printf("Block rejected by %s: %s", u.Name, rpcErr)
+				return
+			}
+			log.Printf("Error submitting block to %s: %s", u.Name, err)
+		}(u)
+	}
+}
+
+// upstreamStats is the per-upstream view exposed over the API.
+type upstreamStats struct {
+	Name             string `json:"name"`
+	Accepts          int64  `json:"accepts"`
+	Rejects          int64  `json:"rejects"`
+	FailsCount       int64  `json:"failsCount"`
+	LastSubmissionAt int64  `json:"lastSubmissionAt"`
+	Sick             bool   `json:"sick"`
+}
+
+// upstreamStatsList returns the current stats for every configured
+// upstream, in configuration order, for exposure over the stats API.
+func (s *StratumServer) upstreamStatsList() []upstreamStats {
+	stats := make([]upstreamStats, 0, len(s.upstreams))
+	for _, u := range s.upstreams {
+		stats = append(stats, upstreamStats{
+			Name:             u.Name,
+			Accepts:          atomic.LoadInt64(&u.Accepts),
+			Rejects:          atomic.LoadInt64(&u.Rejects),
+			FailsCount:       atomic.LoadInt64(&u.FailsCount),
+			LastSubmissionAt: atomic.LoadInt64(&u.LastSubmissionAt),
+			Sick:             u.Sick(),
+		})
+	}
+	return stats
+}
@@ -0,0 +1,190 @@
+package stratum
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"truenft.tech/tnft-stratum/v2/pool"
+	"truenft.tech/tnft-stratum/v2/rpc"
+)
+
+func TestFirstAliveUpstream(t *testing.T) {
+	cases := []struct {
+		name      string
+		alive     []bool
+		wantIdx   int32
+		wantFound bool
+	}{
+		{"first is alive", []bool{true, true, false}, 0, true},
+		{"first is sick, second alive", []bool{false, true, true}, 1, true},
+		{"only last is alive", []bool{false, false, true}, 2, true},
+		{"all sick", []bool{false, false, false}, 0, false},
+		{"empty", []bool{}, 0, false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			idx, found := firstAliveUpstream(c.alive)
+			if found != c.wantFound {
+				t.Fatalf("found = %v, want %v", found, c.wantFound)
+			}
+			if found && idx != c.wantIdx {
+				t.Fatalf("idx = %v, want %v", idx, c.wantIdx)
+			}
+		})
+	}
+}
+
+// newTestUpstream starts an httptest server handling json_rpc requests and
+// returns an RPCClient pointed at it, so checkUpstreams/broadcastSubmitBlock
+// can be exercised against canned daemon responses instead of a live node.
+func newTestUpstream(t *testing.T, name string, handler http.HandlerFunc) *rpc.RPCClient {
+	t.Helper()
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+
+	u, err := url.Parse(srv.URL)
+	if err != nil {
+		t.Fatalf("parsing test server URL: %s", err)
+	}
+	host, portStr, err := net.SplitHostPort(u.Host)
+	if err != nil {
+		t.Fatalf("splitting host/port: %s", err)
+	}
+	port, err := strconv.Atoi(portStr)
+	if err != nil {
+		t.Fatalf("parsing port: %s", err)
+	}
+
+	client, err := rpc.NewRPCClient(&pool.Upstream{Name: name, Host: host, Port: port, Timeout: "2s"})
+	if err != nil {
+		t.Fatalf("NewRPCClient: %s", err)
+	}
+	return client
+}
+
+func healthyDaemon(result string, submitErr *string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&req)
+
+		if req["method"] == "submitblock" && submitErr != nil {
+			w.Write([]byte(`{"jsonrpc":"2.0","id":0,"error":{"code":-7,"message":"` + *submitErr + `"}}`))
+			return
+		}
+		w.Write([]byte(`{"jsonrpc":"2.0","id":0,"result":` + result + `}`))
+	}
+}
+
+func sickDaemon() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+}
+
+const blockTemplateResult = `{"difficulty":1,"height":1,"blocktemplate_blob":"","reserved_offset":0,"prev_hash":"abc","seed_hash":"","next_seed_hash":""}`
+
+func TestCheckUpstreamsSwitchesToFirstHealthy(t *testing.T) {
+	bad := newTestUpstream(t, "bad", sickDaemon())
+	good := newTestUpstream(t, "good", healthyDaemon(blockTemplateResult, nil))
+
+	s := &StratumServer{
+		config:    &pool.Config{Address: "addr"},
+		upstreams: []*rpc.RPCClient{bad, good},
+	}
+
+	s.checkUpstreams()
+
+	if got := atomic.LoadInt32(&s.upstream); got != 1 {
+		t.Fatalf("s.upstream = %v, want 1 (the healthy upstream)", got)
+	}
+}
+
+func TestCheckUpstreamsLeavesSelectionWhenAllSick(t *testing.T) {
+	bad1 := newTestUpstream(t, "bad1", sickDaemon())
+	bad2 := newTestUpstream(t, "bad2", sickDaemon())
+
+	s := &StratumServer{
+		config:    &pool.Config{Address: "addr"},
+		upstreams: []*rpc.RPCClient{bad1, bad2},
+	}
+
+	s.checkUpstreams()
+
+	if got := atomic.LoadInt32(&s.upstream); got != 0 {
+		t.Fatalf("s.upstream = %v, want 0 (untouched)", got)
+	}
+}
+
+func TestUpstreamStatsList(t *testing.T) {
+	u1 := &rpc.RPCClient{Name: "a", Accepts: 10, Rejects: 2, FailsCount: 1, LastSubmissionAt: 123}
+	u2 := &rpc.RPCClient{Name: "b"}
+	s := &StratumServer{upstreams: []*rpc.RPCClient{u1, u2}}
+
+	stats := s.upstreamStatsList()
+	if len(stats) != 2 {
+		t.Fatalf("len(stats) = %v, want 2", len(stats))
+	}
+	if stats[0].Name != "a" || stats[0].Accepts != 10 || stats[0].Rejects != 2 ||
+		stats[0].FailsCount != 1 || stats[0].LastSubmissionAt != 123 {
+		t.Fatalf("stats[0] = %+v, want accepts/rejects/fails/lastSubmission copied from the upstream", stats[0])
+	}
+	if stats[1].Name != "b" {
+		t.Fatalf("stats[1].Name = %q, want %q", stats[1].Name, "b")
+	}
+}
+
+func TestBroadcastSubmitBlockSkipsSickUpstreams(t *testing.T) {
+	healthy := newTestUpstream(t, "healthy", healthyDaemon("null", nil))
+	sickSubmitted := make(chan struct{}, 1)
+	sick := newTestUpstream(t, "sick", func(w http.ResponseWriter, r *http.Request) {
+		var req map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&req)
+		if req["method"] == "submitblock" {
+			select {
+			case sickSubmitted <- struct{}{}:
+			default:
+			}
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+	// Drive the "sick" upstream's Check() to fail so Sick() reports true,
+	// the same way checkUpstreams would before broadcastSubmitBlock runs.
+	for i := 0; i < 5; i++ {
+		sick.Check(8, "addr")
+	}
+	if !sick.Sick() {
+		t.Fatal("expected the upstream to be sick after repeated failed checks")
+	}
+
+	sessions := newSessionManager(0, 0)
+	sub := newTestSubscriber("listener")
+	sessions.add(sub)
+	sessions.subscribe(sub.id, EventBlockFound)
+
+	s := &StratumServer{
+		upstreams: []*rpc.RPCClient{healthy, sick},
+		sessions:  sessions,
+	}
+
+	s.broadcastSubmitBlock("deadbeef")
+
+	select {
+	case <-sub.out:
+	case <-time.After(time.Second):
+		t.Fatal("expected a block.found notification from the healthy upstream's submission")
+	}
+
+	select {
+	case <-sickSubmitted:
+		t.Fatal("expected broadcastSubmitBlock not to submit to the sick upstream")
+	case <-time.After(100 * time.Millisecond):
+	}
+}